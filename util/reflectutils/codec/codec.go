@@ -0,0 +1,107 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codec turns the StructFieldValueSet produced by
+// reflectutils.FetchStructFieldValueSet into a tag-driven (un)marshaler, so
+// that JSON and YAML encoding share a single field-resolution pass instead
+// of each caller re-walking reflect data on its own.
+package codec
+
+import (
+	"fmt"
+	"reflect"
+
+	"yunion.io/x/pkg/util/reflectutils"
+)
+
+// MarshalHook lets a caller override how a single field's Go value is
+// turned into the value that gets encoded.
+type MarshalHook func(v reflect.Value) (interface{}, error)
+
+// UnmarshalHook lets a caller override how a single field is populated from
+// its decoded counterpart.
+type UnmarshalHook func(v reflect.Value, decoded interface{}) error
+
+// Options configures a Codec. The zero value is a usable, hook-free Options.
+type Options struct {
+	// MarshalHooks and UnmarshalHooks are keyed by MarshalName().
+	MarshalHooks   map[string]MarshalHook
+	UnmarshalHooks map[string]UnmarshalHook
+}
+
+// resolvedField is a single field ready for encoding: its wire name and the
+// Go value (after any registered hook has been applied).
+type resolvedField struct {
+	Name  string
+	Value reflect.Value
+}
+
+// resolveForMarshal walks v (a struct or pointer to one) via
+// FetchStructFieldValueSet and returns the ordered, non-ignored fields to
+// encode. A field promoted through a nil anonymous pointer embed has no
+// value to encode and is skipped, the same as if the whole embed were
+// omitted.
+func resolveForMarshal(v interface{}) ([]resolvedField, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("codec: nil pointer passed to Marshal")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("codec: Marshal requires a struct or struct pointer, got %s", rv.Kind())
+	}
+
+	set := reflectutils.FetchStructFieldValueSet(rv)
+	fields := make([]resolvedField, 0, len(set))
+	for _, sv := range set {
+		if sv.Info.Ignore || !sv.Value.IsValid() {
+			continue
+		}
+		fields = append(fields, resolvedField{
+			Name:  sv.Info.MarshalName(),
+			Value: sv.Value,
+		})
+	}
+	return fields, nil
+}
+
+// addressableFields walks v (which must be a non-nil pointer to a struct)
+// via FetchStructFieldValueSet and returns the ordered, non-ignored,
+// addressable fields to decode into. A field promoted through a nil
+// anonymous pointer embed has nowhere to decode into and is skipped.
+func addressableFields(v interface{}) ([]resolvedField, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, fmt.Errorf("codec: Unmarshal requires a non-nil pointer, got %s", rv.Kind())
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("codec: Unmarshal requires a pointer to struct, got pointer to %s", rv.Kind())
+	}
+
+	set := reflectutils.FetchStructFieldValueSet(rv)
+	fields := make([]resolvedField, 0, len(set))
+	for _, sv := range set {
+		if sv.Info.Ignore || !sv.Value.IsValid() {
+			continue
+		}
+		fields = append(fields, resolvedField{
+			Name:  sv.Info.MarshalName(),
+			Value: sv.Value,
+		})
+	}
+	return fields, nil
+}
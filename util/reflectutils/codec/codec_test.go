@@ -0,0 +1,120 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"reflect"
+	"testing"
+)
+
+type sAddr struct {
+	City string `name:"city"`
+}
+
+type sPerson struct {
+	sAddr
+	Name string `name:"name"`
+	Age  int    `name:"age"`
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	in := sPerson{
+		sAddr: sAddr{City: "Beijing"},
+		Name:  "emily",
+		Age:   30,
+	}
+
+	c := NewJSONCodec(Options{})
+	data, err := c.Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	want := `{"city":"Beijing","name":"emily","age":30}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+
+	var out sPerson
+	if err := c.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if out != in {
+		t.Errorf("Unmarshal = %+v, want %+v", out, in)
+	}
+}
+
+func TestYAMLCodecRoundTrip(t *testing.T) {
+	in := sPerson{
+		sAddr: sAddr{City: "Beijing"},
+		Name:  "emily",
+		Age:   30,
+	}
+
+	c := NewYAMLCodec(Options{})
+	data, err := c.Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var out sPerson
+	if err := c.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if out != in {
+		t.Errorf("Unmarshal = %+v, want %+v", out, in)
+	}
+}
+
+func TestJSONCodecMarshalNilPointerEmbed(t *testing.T) {
+	type sPersonPtr struct {
+		*sAddr
+		Name string `name:"name"`
+	}
+
+	in := sPersonPtr{Name: "emily"}
+	c := NewJSONCodec(Options{})
+	data, err := c.Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	want := `{"name":"emily"}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+}
+
+func TestJSONCodecMarshalHook(t *testing.T) {
+	in := sPerson{Name: "emily", Age: 30}
+
+	c := NewJSONCodec(Options{
+		MarshalHooks: map[string]MarshalHook{
+			"age": func(v reflect.Value) (interface{}, error) {
+				return v.Int() + 1, nil
+			},
+		},
+	})
+
+	data, err := c.Marshal(&in)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	want := `{"city":"","name":"emily","age":31}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+}
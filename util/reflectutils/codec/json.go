@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONCodec marshals and unmarshals structs field-by-field using the tag
+// resolution rules of reflectutils, producing deterministic key ordering
+// (the struct's own field order) instead of encoding/json's alphabetical map
+// ordering.
+type JSONCodec struct {
+	opts Options
+}
+
+// NewJSONCodec returns a JSONCodec configured with opts.
+func NewJSONCodec(opts Options) *JSONCodec {
+	return &JSONCodec{opts: opts}
+}
+
+// Marshal encodes v (a struct or pointer to one) to JSON.
+func (c *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	fields, err := resolveForMarshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		var raw interface{}
+		if hook, ok := c.opts.MarshalHooks[f.Name]; ok {
+			raw, err = hook(f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("codec: marshal hook for %q: %w", f.Name, err)
+			}
+		} else {
+			raw = f.Value.Interface()
+		}
+
+		keyJSON, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		valJSON, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("codec: marshal field %q: %w", f.Name, err)
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+		buf.Write(valJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes JSON data into v (a non-nil pointer to a struct).
+func (c *JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	fields, err := addressableFields(v)
+	if err != nil {
+		return err
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("codec: unmarshal: %w", err)
+	}
+
+	for _, f := range fields {
+		msg, ok := raw[f.Name]
+		if !ok {
+			continue
+		}
+		if hook, ok := c.opts.UnmarshalHooks[f.Name]; ok {
+			var decoded interface{}
+			if err := json.Unmarshal(msg, &decoded); err != nil {
+				return fmt.Errorf("codec: unmarshal field %q: %w", f.Name, err)
+			}
+			if err := hook(f.Value, decoded); err != nil {
+				return fmt.Errorf("codec: unmarshal hook for %q: %w", f.Name, err)
+			}
+			continue
+		}
+		if !f.Value.CanAddr() {
+			continue
+		}
+		if err := json.Unmarshal(msg, f.Value.Addr().Interface()); err != nil {
+			return fmt.Errorf("codec: unmarshal field %q: %w", f.Name, err)
+		}
+	}
+	return nil
+}
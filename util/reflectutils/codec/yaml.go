@@ -0,0 +1,100 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLCodec marshals and unmarshals structs field-by-field using the same
+// tag resolution rules as JSONCodec, preserving field order via
+// yaml.MapSlice.
+type YAMLCodec struct {
+	opts Options
+}
+
+// NewYAMLCodec returns a YAMLCodec configured with opts.
+func NewYAMLCodec(opts Options) *YAMLCodec {
+	return &YAMLCodec{opts: opts}
+}
+
+// Marshal encodes v (a struct or pointer to one) to YAML.
+func (c *YAMLCodec) Marshal(v interface{}) ([]byte, error) {
+	fields, err := resolveForMarshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(yaml.MapSlice, 0, len(fields))
+	for _, f := range fields {
+		var raw interface{}
+		if hook, ok := c.opts.MarshalHooks[f.Name]; ok {
+			raw, err = hook(f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("codec: marshal hook for %q: %w", f.Name, err)
+			}
+		} else {
+			raw = f.Value.Interface()
+		}
+		items = append(items, yaml.MapItem{Key: f.Name, Value: raw})
+	}
+	return yaml.Marshal(items)
+}
+
+// Unmarshal decodes YAML data into v (a non-nil pointer to a struct).
+func (c *YAMLCodec) Unmarshal(data []byte, v interface{}) error {
+	fields, err := addressableFields(v)
+	if err != nil {
+		return err
+	}
+
+	var items yaml.MapSlice
+	if err := yaml.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("codec: unmarshal: %w", err)
+	}
+	raw := make(map[interface{}]interface{}, len(items))
+	for _, item := range items {
+		raw[item.Key] = item.Value
+	}
+
+	for _, f := range fields {
+		decoded, ok := raw[f.Name]
+		if !ok {
+			continue
+		}
+		if hook, ok := c.opts.UnmarshalHooks[f.Name]; ok {
+			if err := hook(f.Value, decoded); err != nil {
+				return fmt.Errorf("codec: unmarshal hook for %q: %w", f.Name, err)
+			}
+			continue
+		}
+		if !f.Value.CanAddr() {
+			continue
+		}
+		// Round-trip the single value through yaml so it lands in the
+		// field's concrete type regardless of how yaml decoded it
+		// generically (map[interface{}]interface{}, []interface{}, ...).
+		encoded, err := yaml.Marshal(decoded)
+		if err != nil {
+			return fmt.Errorf("codec: unmarshal field %q: %w", f.Name, err)
+		}
+		if err := yaml.Unmarshal(encoded, f.Value.Addr().Interface()); err != nil {
+			return fmt.Errorf("codec: unmarshal field %q: %w", f.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configloader populates a struct from CLI flags, environment
+// variables and a config file using reflectutils.FetchStructFieldValueSet,
+// in the spirit of Traefik's paerser: field names come from MarshalName(),
+// defaults from the `default:"..."` tag, and help text from a new
+// `help:"..."` tag. Sources are consulted in the order passed to Load, so
+// the first source with a value for a field wins; an unset field falls
+// back to its `default` tag.
+package configloader
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"yunion.io/x/pkg/util/reflectutils"
+)
+
+// Source is a single place configuration values can come from: CLI flags,
+// environment variables, a config file, ...
+type Source interface {
+	// Name identifies the source in error messages.
+	Name() string
+	// Lookup returns the raw string value configured for the field whose
+	// MarshalName() is name, and whether one was found.
+	Lookup(name string) (string, bool)
+}
+
+// Field describes one leaf field of the struct passed to Load, as needed by
+// a Source implementation or by Help.
+type Field struct {
+	Name    string
+	Default string
+	Help    string
+	Kind    reflect.Kind
+}
+
+// Fields returns the leaf fields of cfg (a struct or pointer to one), in the
+// order reflectutils.FetchStructFieldValueSet resolves them.
+func Fields(cfg interface{}) []Field {
+	rv := reflect.ValueOf(cfg)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	set := reflectutils.FetchStructFieldValueSet(rv)
+	fields := make([]Field, 0, len(set))
+	for _, sv := range set {
+		if sv.Info.Ignore {
+			continue
+		}
+		fields = append(fields, Field{
+			Name:    sv.Info.MarshalName(),
+			Default: sv.Info.Tags["default"],
+			Help:    sv.Info.Tags["help"],
+			Kind:    sv.Value.Kind(),
+		})
+	}
+	return fields
+}
+
+// Load populates cfg (a non-nil pointer to a struct) from sources, consulted
+// in the order given; the first source with a value for a field wins. A
+// field with no value from any source falls back to its `default` tag, if
+// any.
+func Load(cfg interface{}, sources ...Source) error {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("configloader: Load requires a non-nil pointer, got %s", rv.Kind())
+	}
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("configloader: Load requires a pointer to struct, got pointer to %s", rv.Kind())
+	}
+
+	set := reflectutils.FetchStructFieldValueSet(rv)
+	for _, sv := range set {
+		if sv.Info.Ignore || !sv.Value.CanSet() {
+			continue
+		}
+		name := sv.Info.MarshalName()
+
+		raw, found := "", false
+		for _, src := range sources {
+			if v, ok := src.Lookup(name); ok {
+				raw, found = v, true
+				break
+			}
+		}
+		if !found {
+			raw, found = sv.Info.Tags["default"], sv.Info.Tags["default"] != ""
+		}
+		if !found {
+			continue
+		}
+
+		if err := setFieldFromString(sv.Value, raw); err != nil {
+			return fmt.Errorf("configloader: field %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Help renders a `--help`-style listing of every leaf field of cfg, walking
+// embedded structs, one "--name\thelp (default: x)" line per field.
+func Help(cfg interface{}) string {
+	var b strings.Builder
+	for _, f := range Fields(cfg) {
+		b.WriteString("--")
+		b.WriteString(f.Name)
+		if f.Help != "" {
+			b.WriteByte('\t')
+			b.WriteString(f.Help)
+		}
+		if f.Default != "" {
+			b.WriteString(" (default: ")
+			b.WriteString(f.Default)
+			b.WriteByte(')')
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func setFieldFromString(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice element kind %s", v.Type().Elem().Kind())
+		}
+		parts := strings.Split(raw, ",")
+		out := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			out.Index(i).SetString(strings.TrimSpace(p))
+		}
+		v.Set(out)
+	default:
+		return fmt.Errorf("unsupported field kind %s", v.Kind())
+	}
+	return nil
+}
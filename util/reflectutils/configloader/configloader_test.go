@@ -0,0 +1,111 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configloader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeSource struct {
+	name   string
+	values map[string]string
+}
+
+func (s *fakeSource) Name() string { return s.name }
+
+func (s *fakeSource) Lookup(name string) (string, bool) {
+	v, ok := s.values[name]
+	return v, ok
+}
+
+type sServerConfig struct {
+	Host string `name:"host" default:"0.0.0.0" help:"listen address"`
+	Port int    `name:"port" default:"8080" help:"listen port"`
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	high := &fakeSource{name: "flag", values: map[string]string{"port": "9000"}}
+	low := &fakeSource{name: "env", values: map[string]string{"host": "127.0.0.1", "port": "9090"}}
+
+	var cfg sServerConfig
+	if err := Load(&cfg, high, low); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Host != "127.0.0.1" {
+		t.Errorf("Host = %q, want from lower-priority source since flag didn't set it", cfg.Host)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Port = %d, want 9000 from the higher-priority source", cfg.Port)
+	}
+}
+
+func TestLoadFallsBackToDefault(t *testing.T) {
+	var cfg sServerConfig
+	if err := Load(&cfg); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Host != "0.0.0.0" || cfg.Port != 8080 {
+		t.Errorf("Load() = %+v, want defaults", cfg)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	help := Help(&sServerConfig{})
+	for _, want := range []string{"--host", "listen address", "--port", "listen port"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("Help() = %q, missing %q", help, want)
+		}
+	}
+}
+
+func TestNewFileSourceYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("host: 127.0.0.1\nport: 9090\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	src, err := NewFileSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSource: %v", err)
+	}
+	var cfg sServerConfig
+	if err := Load(&cfg, src); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Host != "127.0.0.1" || cfg.Port != 9090 {
+		t.Errorf("Load() = %+v, want {127.0.0.1 9090}", cfg)
+	}
+}
+
+func TestNewFileSourceTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	contents := "# comment\nhost = \"127.0.0.1\"\nport = 9090\n"
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	src, err := NewFileSource(path)
+	if err != nil {
+		t.Fatalf("NewFileSource: %v", err)
+	}
+	var cfg sServerConfig
+	if err := Load(&cfg, src); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Host != "127.0.0.1" || cfg.Port != 9090 {
+		t.Errorf("Load() = %+v, want {127.0.0.1 9090}", cfg)
+	}
+}
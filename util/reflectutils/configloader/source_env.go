@@ -0,0 +1,37 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configloader
+
+import (
+	"os"
+	"strings"
+)
+
+// EnvSource reads values from environment variables named after a field's
+// MarshalName(), upper-cased with "-" turned into "_" and optionally
+// prefixed, e.g. field "db-host" with Prefix "MYAPP" looks up "MYAPP_DB_HOST".
+type EnvSource struct {
+	Prefix string
+}
+
+func (s *EnvSource) Name() string { return "env" }
+
+func (s *EnvSource) Lookup(name string) (string, bool) {
+	key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if s.Prefix != "" {
+		key = strings.ToUpper(s.Prefix) + "_" + key
+	}
+	return os.LookupEnv(key)
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FileSource reads values from a YAML or TOML config file, keyed at the top
+// level by each field's MarshalName(). The format is chosen from path's
+// extension: ".toml" parses as TOML, anything else (".yaml", ".yml", ...)
+// as YAML.
+//
+// TOML support is intentionally thin: only a flat, single-table file is
+// understood (plain `key = value` lines; no `[section]` headers, arrays, or
+// inline tables), since FileSource never looks up anything but a top-level
+// key. A TOML file using any of those constructs is rejected with a parse
+// error rather than silently misread; use YAML if that's a problem.
+type FileSource struct {
+	path string
+	data map[string]interface{}
+}
+
+// NewFileSource reads and parses path, picking YAML or TOML by its
+// extension. See FileSource for the subset of TOML that's understood.
+func NewFileSource(path string) (*FileSource, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("configloader: reading %s: %w", path, err)
+	}
+	data := map[string]interface{}{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		if err := unmarshalFlatTOML(raw, data); err != nil {
+			return nil, fmt.Errorf("configloader: parsing %s: %w", path, err)
+		}
+	} else if err := yaml.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("configloader: parsing %s: %w", path, err)
+	}
+	return &FileSource{path: path, data: data}, nil
+}
+
+func (s *FileSource) Name() string { return "file:" + s.path }
+
+func (s *FileSource) Lookup(name string) (string, bool) {
+	v, ok := s.data[name]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%v", v), true
+}
+
+// unmarshalFlatTOML parses the top-level `key = value` assignments of raw
+// into out. Only the flat, single-table subset of TOML is supported, since
+// FileSource only ever looks up a field's MarshalName() at the top level: no
+// `[section]` headers, arrays, or inline tables, each of which is an error. A
+// value is a double-quoted string, true/false, or a bare integer/float;
+// anything else is kept as the raw unquoted text.
+func unmarshalFlatTOML(raw []byte, out map[string]interface{}) error {
+	for lineNo, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return fmt.Errorf("line %d: table sections are not supported: %q", lineNo+1, line)
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("line %d: expected \"key = value\", got %q", lineNo+1, line)
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		if strings.HasPrefix(val, "[") || strings.HasPrefix(val, "{") {
+			return fmt.Errorf("line %d: arrays and inline tables are not supported: %q", lineNo+1, line)
+		}
+
+		switch {
+		case len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"':
+			out[key] = val[1 : len(val)-1]
+		case val == "true":
+			out[key] = true
+		case val == "false":
+			out[key] = false
+		default:
+			if n, err := strconv.ParseInt(val, 10, 64); err == nil {
+				out[key] = n
+			} else if f, err := strconv.ParseFloat(val, 64); err == nil {
+				out[key] = f
+			} else {
+				out[key] = val
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configloader
+
+import (
+	"flag"
+)
+
+// FlagSource reads values from a parsed flag.FlagSet. Only flags the user
+// actually passed on the command line count as "found" -- an unvisited flag
+// falls through to the next Source (or the `default` tag) rather than
+// shadowing it with the flag package's own zero-value default.
+type FlagSource struct {
+	fs      *flag.FlagSet
+	visited map[string]string
+}
+
+// NewFlagSource wraps fs (flag.CommandLine if nil), which must already have
+// been Parse()d. Register the struct's fields as flags first with
+// RegisterFlags.
+func NewFlagSource(fs *flag.FlagSet) *FlagSource {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	visited := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		visited[f.Name] = f.Value.String()
+	})
+	return &FlagSource{fs: fs, visited: visited}
+}
+
+// RegisterFlags registers every leaf field of cfg as a string flag on fs,
+// named after MarshalName() and documented from the `help` tag, so that
+// `--help` enumerates them. Call flag.Parse (or fs.Parse) afterwards, then
+// build a FlagSource from the same fs.
+func RegisterFlags(fs *flag.FlagSet, cfg interface{}) {
+	for _, f := range Fields(cfg) {
+		fs.String(f.Name, f.Default, f.Help)
+	}
+}
+
+func (s *FlagSource) Name() string { return "flag" }
+
+func (s *FlagSource) Lookup(name string) (string, bool) {
+	v, ok := s.visited[name]
+	return v, ok
+}
@@ -0,0 +1,374 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"yunion.io/x/pkg/util/reflectutils/tagparser"
+)
+
+// SStructFieldInfo records the resolved name and raw tags of a single
+// struct field, after applying any embedded-struct tag overrides.
+type SStructFieldInfo struct {
+	Name   string
+	Ignore bool
+	Tags   map[string]string
+
+	autoName  string
+	conflicts []conflictRecord
+}
+
+// conflictRecord notes that two override expressions from the same embed
+// tag disagreed on the value of Key, so Set.Lint can report it.
+type conflictRecord struct {
+	Key    string
+	Values []string
+}
+
+// MarshalName returns the name that should be used when the field is
+// serialized. It falls back to the field's default (snake_case) name
+// when the field has been excluded from its own Name (e.g. `json:"-"`).
+func (info *SStructFieldInfo) MarshalName() string {
+	if info.Name != "" {
+		return info.Name
+	}
+	return info.autoName
+}
+
+// ParseFieldJsonInfo resolves the SStructFieldInfo for a field given its
+// Go name and raw struct tag.
+func ParseFieldJsonInfo(name string, tag reflect.StructTag) SStructFieldInfo {
+	tags := parseStructTag(string(tag))
+	autoName := camelToSnake(name)
+
+	info := SStructFieldInfo{Tags: tags, autoName: autoName}
+
+	if nameTag, ok := tags["name"]; ok {
+		info.Name = nameTag
+		return info
+	}
+
+	if jsonTag, ok := tags["json"]; ok {
+		switch {
+		case jsonTag == "-":
+			info.Name = ""
+			info.Ignore = true
+		case jsonTag == "-,":
+			info.Name = "-"
+		default:
+			info.Name = strings.Split(jsonTag, ",")[0]
+		}
+		return info
+	}
+
+	info.Name = autoName
+	return info
+}
+
+// ParseStructFieldJsonInfo resolves the SStructFieldInfo for a reflect.StructField.
+func ParseStructFieldJsonInfo(sf reflect.StructField) SStructFieldInfo {
+	return ParseFieldJsonInfo(sf.Name, sf.Tag)
+}
+
+// SStructFieldValue pairs a resolved SStructFieldInfo with the reflect.Value
+// and index path of the concrete field it was resolved from.
+type SStructFieldValue struct {
+	Info    SStructFieldInfo
+	Value   reflect.Value
+	Indexes []int
+}
+
+// SStructFieldValueSet is the flattened list of leaf fields of a struct,
+// including fields promoted from embedded (anonymous) structs.
+type SStructFieldValueSet []SStructFieldValue
+
+// GetStructFieldIndexes returns the index paths of every leaf field whose
+// resolved Name or MarshalName matches name. More than one result means the
+// name is ambiguous (e.g. it is promoted from more than one embedded struct).
+func (set SStructFieldValueSet) GetStructFieldIndexes(name string) [][]int {
+	ret := make([][]int, 0)
+	for _, sv := range set {
+		if sv.Info.Name == name || sv.Info.MarshalName() == name {
+			ret = append(ret, sv.Indexes)
+		}
+	}
+	return ret
+}
+
+// FetchStructFieldValueSet walks rv (which must be a struct or a pointer to
+// one) and returns the flattened set of its leaf fields, honoring embedded
+// struct promotion and the tag-override conventions documented alongside
+// TestOverrideStructTags.
+//
+// The value-independent part of the walk (field indexes, parsed tags,
+// marshal names, override chain) is memoized per reflect.Type in
+// DefaultTypeCache; only the binding of reflect.Value via FieldByIndex is
+// redone on every call.
+func FetchStructFieldValueSet(rv reflect.Value) SStructFieldValueSet {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return SStructFieldValueSet{}
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return SStructFieldValueSet{}
+	}
+
+	schema := DefaultTypeCache.schemaFor(rv.Type())
+	set := make(SStructFieldValueSet, len(schema))
+	for i, s := range schema {
+		set[i] = SStructFieldValue{
+			Info:    s.Info,
+			Value:   fieldByIndex(rv, s.Indexes),
+			Indexes: s.Indexes,
+		}
+	}
+	return set
+}
+
+// fieldByIndex is a nil-pointer-safe variant of reflect.Value.FieldByIndex:
+// it returns the zero Value instead of panicking when an embedded pointer
+// along the path is nil.
+func fieldByIndex(rv reflect.Value, indexes []int) reflect.Value {
+	for i, x := range indexes {
+		if i > 0 {
+			if rv.Kind() == reflect.Ptr {
+				if rv.IsNil() {
+					return reflect.Value{}
+				}
+				rv = rv.Elem()
+			}
+		}
+		rv = rv.Field(x)
+	}
+	return rv
+}
+
+// overrideIntent is one pending "set tag Key to Value on sub[Idx]", gathered
+// before being applied so that conflicts between two keys of the very same
+// embed tag (e.g. a broadcast `default:"a"` and a path-qualified
+// `"status->default":"b"` that both resolve to the same field) can be
+// detected independent of Go's randomized map iteration order.
+type overrideIntent struct {
+	Idx int
+	Key string
+	Val string
+}
+
+// expressionIntents resolves the ";"-separated tagparser.ParseTagExpression
+// expressions carried by a tagparser.RegisterTagOverride key's value against
+// the already-resolved leaf fields in sub, the same way a path-qualified
+// key does. A malformed expression is skipped rather than applied, since
+// there is no tag key of its own to fall back to treating it as opaque.
+func expressionIntents(sub SStructFieldValueSet, val string) []overrideIntent {
+	var intents []overrideIntent
+	for _, expr := range strings.Split(val, ";") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		node, err := tagparser.ParseTagExpression(expr)
+		if err != nil {
+			continue
+		}
+		// As with the ParsePath case below, only the first field-name hop is
+		// resolved against already-flattened leaf fields.
+		seg := node.Path[0]
+		for i := range sub {
+			if sub[i].Info.Name != seg.Field && sub[i].Info.MarshalName() != seg.Field {
+				continue
+			}
+			if seg.HasCondition() && sub[i].Info.Tags[seg.CondTag] != seg.CondValue {
+				continue
+			}
+			intents = append(intents, overrideIntent{Idx: i, Key: node.Tag, Val: node.Value})
+		}
+	}
+	return intents
+}
+
+// applyTagOverrides applies the tag overrides declared on an embedded field
+// to the already-resolved leaf fields promoted from it. A plain key (e.g.
+// `update:"admin"`) broadcasts to every leaf field. A path-qualified key
+// (e.g. `"status->default":"online"`, or `"status[create=required]->default":"online"`)
+// is parsed by tagparser.ParsePath and only applies to the leaf field(s)
+// matching the path, optionally guarded by a `field[tag=value]` condition.
+// A key registered via tagparser.RegisterTagOverride instead carries one or
+// more full tagparser.ParseTagExpression expressions as its value; see
+// expressionIntents.
+func applyTagOverrides(sub SStructFieldValueSet, overrides map[string]string) {
+	var intents []overrideIntent
+
+	for key, val := range overrides {
+		if tagparser.IsRegisteredOverride(key) {
+			intents = append(intents, expressionIntents(sub, val)...)
+			continue
+		}
+
+		if !strings.Contains(key, "->") {
+			for i := range sub {
+				intents = append(intents, overrideIntent{Idx: i, Key: key, Val: val})
+			}
+			continue
+		}
+
+		segs, tagKey, err := tagparser.ParsePath(key)
+		if err != nil {
+			// not a well-formed path expression; fall back to treating it
+			// as an opaque tag key rather than dropping the override.
+			for i := range sub {
+				intents = append(intents, overrideIntent{Idx: i, Key: key, Val: val})
+			}
+			continue
+		}
+		// Only a single field-name hop (with an optional condition) is
+		// resolved against already-flattened leaf fields; deeper paths
+		// describe structure within a single field's own tag (e.g. a
+		// nested `validate` expression) and are left for that tag's own
+		// consumer to interpret.
+		seg := segs[0]
+		for i := range sub {
+			if sub[i].Info.Name != seg.Field && sub[i].Info.MarshalName() != seg.Field {
+				continue
+			}
+			if seg.HasCondition() && sub[i].Info.Tags[seg.CondTag] != seg.CondValue {
+				continue
+			}
+			intents = append(intents, overrideIntent{Idx: i, Key: tagKey, Val: val})
+		}
+	}
+
+	// Two intents for the same (field, tag key) within this single embed's
+	// own tag, disagreeing on value, are a genuine conflict: nothing orders
+	// one ahead of the other the way an outer embed's override deterministically
+	// wins over an inner one. Record it on the field before applying so
+	// Set.Lint can surface it later.
+	seenPerField := map[int]map[string]string{}
+	for _, in := range intents {
+		m, ok := seenPerField[in.Idx]
+		if !ok {
+			m = map[string]string{}
+			seenPerField[in.Idx] = m
+		}
+		if prev, ok := m[in.Key]; ok && prev != in.Val {
+			sub[in.Idx].Info.conflicts = append(sub[in.Idx].Info.conflicts, conflictRecord{
+				Key: in.Key, Values: []string{prev, in.Val},
+			})
+		}
+		m[in.Key] = in.Val
+	}
+
+	for _, in := range intents {
+		sub[in.Idx].Info.Tags[in.Key] = in.Val
+	}
+}
+
+// parseStructTag parses a raw struct tag string into a key/value map. Unlike
+// reflect.StructTag, it supports enumerating all keys, and additionally
+// recognizes quoted keys (e.g. `"status->default":"online"`) used by the
+// embedded-struct override convention.
+func parseStructTag(tag string) map[string]string {
+	m := map[string]string{}
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		var key string
+		if tag[0] == '"' {
+			j := 1
+			for j < len(tag) && tag[j] != '"' {
+				j++
+			}
+			if j >= len(tag) {
+				break
+			}
+			key = tag[1:j]
+			tag = tag[j+1:]
+		} else {
+			j := 0
+			for j < len(tag) && tag[j] != ':' && tag[j] != ' ' {
+				j++
+			}
+			if j == 0 {
+				break
+			}
+			key = tag[:j]
+			tag = tag[j:]
+		}
+
+		if len(tag) == 0 || tag[0] != ':' {
+			break
+		}
+		tag = tag[1:]
+		if len(tag) == 0 || tag[0] != '"' {
+			break
+		}
+
+		// Scan for the closing quote the way reflect.StructTag does: a
+		// backslash escapes the character that follows it, so `\"` doesn't
+		// end the value early.
+		j := 1
+		for j < len(tag) && tag[j] != '"' {
+			if tag[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:j+1])
+		if err != nil {
+			break
+		}
+		tag = tag[j+1:]
+		m[key] = value
+	}
+	return m
+}
+
+// camelToSnake converts a CamelCase Go identifier into its snake_case
+// default field name, keeping runs of uppercase letters (acronyms) such as
+// "DB" or "ID" together, e.g. "DBInstanceId" -> "db_instance_id".
+func camelToSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prevLower := unicode.IsLower(runes[i-1])
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -18,6 +18,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"yunion.io/x/pkg/util/reflectutils/tagparser"
 )
 
 func TestParseStructFieldJsonInfo_Name(t *testing.T) {
@@ -266,3 +268,34 @@ func TestOverrideStructTags(t *testing.T) {
 		}
 	}
 }
+
+func TestOverrideStructTagsExpression(t *testing.T) {
+	tagparser.RegisterTagOverride("override")
+
+	type StatusBase struct {
+		Status string `default:"init"`
+	}
+	type EnabledBase struct {
+		Enabled *bool `default:"false"`
+	}
+	type Compond struct {
+		StatusBase
+		EnabledBase
+	}
+	type TopStruct struct {
+		Compond `override:"status->default:\"online\"; enabled->default:\"true\""`
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(TopStruct{}))
+	got := make(map[string]string)
+	for _, s := range set {
+		got[s.Info.MarshalName()] = s.Info.Tags["default"]
+	}
+	want := map[string]string{
+		"status":  "online",
+		"enabled": "true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got: %v Want: %v", got, want)
+	}
+}
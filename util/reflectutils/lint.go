@@ -0,0 +1,142 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAmbiguousField is the sentinel a caller can match against with
+// errors.Is; GetStructFieldIndex actually returns the richer
+// *AmbiguousFieldError, which wraps it.
+var ErrAmbiguousField = errors.New("reflectutils: ambiguous field")
+
+// AmbiguousFieldError is returned by GetStructFieldIndex when a name is
+// promoted from more than one embedded struct at the same depth, mirroring
+// Go's own rule that such a field is inaccessible by selector.
+type AmbiguousFieldError struct {
+	Name       string
+	Candidates [][]int
+}
+
+func (e *AmbiguousFieldError) Error() string {
+	return fmt.Sprintf("reflectutils: field %q is ambiguous between %d promoted fields at the same depth: %v",
+		e.Name, len(e.Candidates), e.Candidates)
+}
+
+func (e *AmbiguousFieldError) Unwrap() error {
+	return ErrAmbiguousField
+}
+
+// GetStructFieldIndex resolves name to a single index path, applying Go's
+// own embedding promotion rule: the shallowest match wins; if more than one
+// candidate shares the shallowest depth, the name is ambiguous and an
+// *AmbiguousFieldError is returned (with every candidate at that depth) just
+// as Go itself refuses to pick one for `x.Name`.
+func (set SStructFieldValueSet) GetStructFieldIndex(name string) ([]int, error) {
+	all := set.GetStructFieldIndexes(name)
+	if len(all) == 0 {
+		return nil, fmt.Errorf("reflectutils: no field named %q", name)
+	}
+
+	shallowest := len(all[0])
+	for _, idx := range all[1:] {
+		if len(idx) < shallowest {
+			shallowest = len(idx)
+		}
+	}
+
+	var winners [][]int
+	for _, idx := range all {
+		if len(idx) == shallowest {
+			winners = append(winners, idx)
+		}
+	}
+	if len(winners) > 1 {
+		return nil, &AmbiguousFieldError{Name: name, Candidates: winners}
+	}
+	return winners[0], nil
+}
+
+// Diagnostic is a single issue found by Set.Lint.
+type Diagnostic struct {
+	// Severity is "error" for configuration that will silently misbehave
+	// (e.g. an unresolved name ambiguity) or "warning" for configuration
+	// that is legal but likely a mistake (e.g. a shadowed field).
+	Severity string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("[%s] %s", d.Severity, d.Message)
+}
+
+// Lint reports configuration problems in set that GetStructFieldIndex and
+// MarshalName would otherwise resolve silently (possibly to the wrong
+// field): duplicate marshal names, fields shadowed by a shallower same-named
+// field, and overrides within a single embed's tag that set the same key on
+// the same field to different values (an outer embed overriding an inner
+// one is not reported). Models are expected to call Lint once at
+// startup and fail fast on any "error" diagnostic.
+func (set SStructFieldValueSet) Lint() []Diagnostic {
+	var diags []Diagnostic
+
+	byName := map[string][][]int{}
+	for _, sv := range set {
+		name := sv.Info.MarshalName()
+		byName[name] = append(byName[name], sv.Indexes)
+	}
+	for name, paths := range byName {
+		if len(paths) < 2 {
+			continue
+		}
+		shallowest := len(paths[0])
+		for _, p := range paths[1:] {
+			if len(p) < shallowest {
+				shallowest = len(p)
+			}
+		}
+		var atShallowest int
+		for _, p := range paths {
+			if len(p) == shallowest {
+				atShallowest++
+			}
+		}
+		if atShallowest > 1 {
+			diags = append(diags, Diagnostic{
+				Severity: "error",
+				Message:  fmt.Sprintf("marshal name %q is ambiguous between %d fields at depth %d: %v", name, atShallowest, shallowest, paths),
+			})
+			continue
+		}
+		diags = append(diags, Diagnostic{
+			Severity: "warning",
+			Message:  fmt.Sprintf("marshal name %q: %d deeper field(s) are shadowed by a shallower field and unreachable: %v", name, len(paths)-1, paths),
+		})
+	}
+
+	for _, sv := range set {
+		for _, c := range sv.Info.conflicts {
+			diags = append(diags, Diagnostic{
+				Severity: "error",
+				Message: fmt.Sprintf("field %q: conflicting override expressions for tag %q: %v",
+					sv.Info.MarshalName(), c.Key, c.Values),
+			})
+		}
+	}
+
+	return diags
+}
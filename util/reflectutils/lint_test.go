@@ -0,0 +1,154 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGetStructFieldIndexAmbiguous(t *testing.T) {
+	type Embeded struct {
+		Name string `json:"name"`
+	}
+	type Struct1 struct {
+		Embeded
+		Prop1 string `json:"prop1"`
+	}
+	type Struct2 struct {
+		Embeded
+		Prop2 string `json:"prop2"`
+	}
+	type TopStruct struct {
+		Struct1
+		Struct2
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(TopStruct{}))
+
+	if _, err := set.GetStructFieldIndex("name"); err == nil {
+		t.Fatalf("expected ambiguous error for \"name\"")
+	} else if !errors.Is(err, ErrAmbiguousField) {
+		t.Errorf("expected errors.Is(err, ErrAmbiguousField), got %v", err)
+	}
+
+	idx, err := set.GetStructFieldIndex("prop1")
+	if err != nil {
+		t.Fatalf("GetStructFieldIndex(prop1): %v", err)
+	}
+	if len(idx) == 0 {
+		t.Errorf("expected a non-empty index path for prop1")
+	}
+}
+
+func TestGetStructFieldIndexShallowestWins(t *testing.T) {
+	type Inner struct {
+		Name string `json:"name"`
+	}
+	type Middle struct {
+		Inner
+	}
+	type Outer struct {
+		Middle
+		Name string `json:"name"`
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(Outer{}))
+	idx, err := set.GetStructFieldIndex("name")
+	if err != nil {
+		t.Fatalf("GetStructFieldIndex(name): %v", err)
+	}
+	if len(idx) != 1 {
+		t.Errorf("expected the shallow Outer.Name (depth 1) to win, got index %v", idx)
+	}
+}
+
+func TestLintDetectsAmbiguity(t *testing.T) {
+	type Embeded struct {
+		Name string `json:"name"`
+	}
+	type Struct1 struct {
+		Embeded
+	}
+	type Struct2 struct {
+		Embeded
+	}
+	type TopStruct struct {
+		Struct1
+		Struct2
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(TopStruct{}))
+	diags := set.Lint()
+
+	var foundError bool
+	for _, d := range diags {
+		if d.Severity == "error" {
+			foundError = true
+		}
+	}
+	if !foundError {
+		t.Errorf("Lint() = %v, expected at least one error-severity diagnostic for the ambiguous \"name\"", diags)
+	}
+}
+
+func TestLintDoesNotFlagLegitimateCascadingOverrides(t *testing.T) {
+	// Outer embeds overriding an inner embed's tag (as exercised by
+	// TestOverrideStructTags) is the intended, deterministic "outer wins"
+	// behavior, not a conflict -- Lint must stay quiet about it.
+	type StatusBase struct {
+		Status string `default:"init"`
+	}
+	type Mid struct {
+		StatusBase `default:"a"`
+	}
+	type Top struct {
+		Mid `default:"b"`
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(Top{}))
+	for _, d := range set.Lint() {
+		if d.Severity == "error" {
+			t.Errorf("Lint() reported %v for a legitimate cascading override", d)
+		}
+	}
+}
+
+func TestLintDetectsConflictingOverrides(t *testing.T) {
+	// A single embed tag that both broadcasts `default` and path-qualifies
+	// `status->default` to a different value is genuinely ambiguous: both
+	// expressions come from the same tag, so neither deterministically
+	// wins the way an outer embed wins over an inner one.
+	type StatusBase struct {
+		Status string `default:"init"`
+	}
+	type Top struct {
+		StatusBase `default:"a" "status->default":"b"`
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(Top{}))
+	diags := set.Lint()
+
+	var foundConflict bool
+	for _, d := range diags {
+		if d.Severity == "error" {
+			foundConflict = true
+		}
+	}
+	if !foundConflict {
+		t.Errorf("Lint() = %v, expected a conflicting-override error", diags)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tagparser implements a small grammar for the embedded-struct tag
+// override expressions used by reflectutils, e.g.:
+//
+//	status->default:"online"
+//	enabled->validate->min:"1"
+//	field[tag=value]->default:"x"
+//
+// A parsed expression resolves to a path of field-name segments (optionally
+// guarded by a "field[tag=value]" selector), the tag key to set at the end
+// of that path, and the value to set it to.
+package tagparser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PathSegment is one "->"-separated hop in a tag expression's field path.
+// CondTag/CondValue are only set for the "field[tag=value]" selector form,
+// which restricts the segment to apply only when the target field also
+// carries tag CondTag with value CondValue.
+type PathSegment struct {
+	Field     string
+	CondTag   string
+	CondValue string
+}
+
+// HasCondition reports whether the segment carries a "[tag=value]" selector.
+func (s PathSegment) HasCondition() bool {
+	return s.CondTag != ""
+}
+
+// TagExprNode is the parsed AST of a single tag override expression.
+type TagExprNode struct {
+	Path  []PathSegment
+	Tag   string
+	Value string
+}
+
+// String renders the node back to its canonical expression form, mostly
+// useful for debugging and error messages.
+func (n *TagExprNode) String() string {
+	segs := make([]string, 0, len(n.Path)+1)
+	for _, p := range n.Path {
+		if p.HasCondition() {
+			segs = append(segs, fmt.Sprintf("%s[%s=%s]", p.Field, p.CondTag, p.CondValue))
+		} else {
+			segs = append(segs, p.Field)
+		}
+	}
+	segs = append(segs, n.Tag)
+	return fmt.Sprintf("%s:%q", strings.Join(segs, "->"), n.Value)
+}
+
+// ParseTagExpression parses a full "path->...->tag:\"value\"" expression,
+// such as those accepted by a tag key registered via RegisterTagOverride,
+// into a TagExprNode.
+func ParseTagExpression(expr string) (*TagExprNode, error) {
+	p := &parser{input: expr}
+	return p.parse()
+}
+
+var registeredOverrideKeys = struct {
+	sync.RWMutex
+	keys map[string]bool
+}{keys: map[string]bool{}}
+
+// RegisterTagOverride declares tagKey as holding expression-based tag
+// overrides: instead of being resolved via the `"field->tag":"value"`
+// struct-tag-key convention that ParsePath serves, a tag named tagKey on an
+// embedded field carries one or more ";"-separated full expressions (each
+// parseable by ParseTagExpression) as its value, e.g.:
+//
+//	Embedded `override:"status->default:\"online\"; enabled->default:\"true\""`
+//
+// This lets callers outside this package register their own tag key for the
+// expression grammar without reflectutils hard-coding one. Registration is
+// safe for concurrent use; it is typically done once from an init func.
+func RegisterTagOverride(tagKey string) {
+	registeredOverrideKeys.Lock()
+	defer registeredOverrideKeys.Unlock()
+	registeredOverrideKeys.keys[tagKey] = true
+}
+
+// IsRegisteredOverride reports whether tagKey was declared via
+// RegisterTagOverride.
+func IsRegisteredOverride(tagKey string) bool {
+	registeredOverrideKeys.RLock()
+	defer registeredOverrideKeys.RUnlock()
+	return registeredOverrideKeys.keys[tagKey]
+}
+
+// ParsePath parses just the path+tag portion of an expression (no trailing
+// `:"value"`), as used when resolving an already tag-parsed key such as the
+// one that comes out of a struct tag's key/value pair, e.g.
+// `"status->default":"online"` is parsed as key=`status->default`,
+// value=`online` by the struct tag tokenizer, and the key alone is handed
+// to ParsePath.
+func ParsePath(path string) ([]PathSegment, string, error) {
+	segs, err := splitSegments(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(segs) < 2 {
+		return nil, "", fmt.Errorf("tagparser: expression %q needs at least one field segment and a tag key", path)
+	}
+	fieldSegs := segs[:len(segs)-1]
+	tagKey := segs[len(segs)-1]
+
+	path_ := make([]PathSegment, 0, len(fieldSegs))
+	for _, s := range fieldSegs {
+		seg, err := parseSegment(s)
+		if err != nil {
+			return nil, "", err
+		}
+		path_ = append(path_, seg)
+	}
+	return path_, tagKey, nil
+}
+
+type parser struct {
+	input string
+}
+
+func (p *parser) parse() (*TagExprNode, error) {
+	idx := strings.IndexByte(p.input, ':')
+	if idx < 0 {
+		return nil, fmt.Errorf("tagparser: expression %q missing ':\"value\"'", p.input)
+	}
+	pathPart := p.input[:idx]
+	valuePart := p.input[idx+1:]
+
+	value, err := unquote(valuePart)
+	if err != nil {
+		return nil, fmt.Errorf("tagparser: expression %q has invalid value: %w", p.input, err)
+	}
+
+	segs, tagKey, err := ParsePath(pathPart)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TagExprNode{Path: segs, Tag: tagKey, Value: value}, nil
+}
+
+// splitSegments splits a "a->b[c=d]->e" path on "->", but not inside a
+// "[...]" selector.
+func splitSegments(path string) ([]string, error) {
+	segs := make([]string, 0, 4)
+	depth := 0
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("tagparser: unbalanced ']' in %q", path)
+			}
+		case '-':
+			if depth == 0 && i+1 < len(path) && path[i+1] == '>' {
+				segs = append(segs, path[start:i])
+				i++
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("tagparser: unbalanced '[' in %q", path)
+	}
+	segs = append(segs, path[start:])
+	for _, s := range segs {
+		if strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("tagparser: empty segment in %q", path)
+		}
+	}
+	return segs, nil
+}
+
+// parseSegment parses a single "field" or "field[tag=value]" path segment.
+func parseSegment(seg string) (PathSegment, error) {
+	lb := strings.IndexByte(seg, '[')
+	if lb < 0 {
+		return PathSegment{Field: seg}, nil
+	}
+	if !strings.HasSuffix(seg, "]") {
+		return PathSegment{}, fmt.Errorf("tagparser: segment %q missing closing ']'", seg)
+	}
+	field := seg[:lb]
+	cond := seg[lb+1 : len(seg)-1]
+	eq := strings.IndexByte(cond, '=')
+	if eq < 0 {
+		return PathSegment{}, fmt.Errorf("tagparser: condition %q in segment %q must be tag=value", cond, seg)
+	}
+	return PathSegment{
+		Field:     field,
+		CondTag:   strings.TrimSpace(cond[:eq]),
+		CondValue: strings.TrimSpace(cond[eq+1:]),
+	}, nil
+}
+
+// unquote strips a surrounding pair of double quotes, if present; an
+// unquoted value is accepted verbatim for convenience.
+func unquote(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1], nil
+	}
+	return s, nil
+}
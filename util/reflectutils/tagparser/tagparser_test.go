@@ -0,0 +1,77 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tagparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTagExpression(t *testing.T) {
+	cases := []struct {
+		Expr string
+		Want *TagExprNode
+	}{
+		{
+			Expr: `status->default:"online"`,
+			Want: &TagExprNode{
+				Path:  []PathSegment{{Field: "status"}},
+				Tag:   "default",
+				Value: "online",
+			},
+		},
+		{
+			Expr: `enabled->validate->min:"1"`,
+			Want: &TagExprNode{
+				Path:  []PathSegment{{Field: "enabled"}, {Field: "validate"}},
+				Tag:   "min",
+				Value: "1",
+			},
+		},
+		{
+			Expr: `field[tag=value]->default:"x"`,
+			Want: &TagExprNode{
+				Path:  []PathSegment{{Field: "field", CondTag: "tag", CondValue: "value"}},
+				Tag:   "default",
+				Value: "x",
+			},
+		},
+	}
+	for _, c := range cases {
+		got, err := ParseTagExpression(c.Expr)
+		if err != nil {
+			t.Errorf("ParseTagExpression(%q) error: %v", c.Expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.Want) {
+			t.Errorf("ParseTagExpression(%q) = %#v, want %#v", c.Expr, got, c.Want)
+		}
+	}
+}
+
+func TestParseTagExpressionErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"status",
+		"status:",
+		"field[tag]->default:\"x\"",
+		"field[tag=value->default:\"x\"",
+	}
+	for _, expr := range cases {
+		if _, err := ParseTagExpression(expr); err == nil {
+			t.Errorf("ParseTagExpression(%q) expected error, got nil", expr)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"reflect"
+	"sync"
+)
+
+// schemaField is the value-independent part of a resolved leaf field: its
+// info and the index path used to reach it from the top-level struct.
+type schemaField struct {
+	Info    SStructFieldInfo
+	Indexes []int
+}
+
+// TypeCache memoizes the resolved field schema of a struct type (field
+// indexes, parsed tags, marshal names, override chain), keyed by
+// reflect.Type, so that repeated calls to FetchStructFieldValueSet for the
+// same type only need to bind reflect.Value via FieldByIndex instead of
+// re-walking the struct's tags.
+type TypeCache struct {
+	store sync.Map // reflect.Type -> []schemaField
+}
+
+// DefaultTypeCache is the package-level cache used by FetchStructFieldValueSet.
+var DefaultTypeCache = &TypeCache{}
+
+// Invalidate drops the cached schema for rt, if any, forcing it to be
+// rebuilt on the next FetchStructFieldValueSet call for that type.
+func (c *TypeCache) Invalidate(rt reflect.Type) {
+	c.store.Delete(rt)
+}
+
+// InvalidateAll drops every cached schema.
+func (c *TypeCache) InvalidateAll() {
+	c.store.Range(func(k, _ interface{}) bool {
+		c.store.Delete(k)
+		return true
+	})
+}
+
+func (c *TypeCache) schemaFor(rt reflect.Type) []schemaField {
+	if cached, ok := c.store.Load(rt); ok {
+		return cached.([]schemaField)
+	}
+	schema := buildSchema(rt, nil)
+	actual, _ := c.store.LoadOrStore(rt, schema)
+	return actual.([]schemaField)
+}
+
+// buildSchema walks rt (a struct type) and produces the flattened,
+// value-independent schema of its leaf fields, applying the same
+// embedded-struct tag override rules as FetchStructFieldValueSet.
+//
+// An anonymous field of struct type, or of pointer-to-struct type, is
+// promoted (its own fields are flattened into the result) the same way Go
+// itself promotes both forms for selector and method resolution. A nil
+// pointer embed is handled at bind time by fieldByIndex, which reports the
+// zero reflect.Value for any field promoted through it instead of panicking.
+func buildSchema(rt reflect.Type, indexPrefix []int) []schemaField {
+	schema := make([]schemaField, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		indexes := make([]int, len(indexPrefix), len(indexPrefix)+1)
+		copy(indexes, indexPrefix)
+		indexes = append(indexes, i)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if sf.Anonymous && ft.Kind() == reflect.Struct {
+			sub := buildSchema(ft, indexes)
+			applySchemaOverrides(sub, parseStructTag(string(sf.Tag)))
+			schema = append(schema, sub...)
+			continue
+		}
+
+		schema = append(schema, schemaField{
+			Info:    ParseStructFieldJsonInfo(sf),
+			Indexes: indexes,
+		})
+	}
+	return schema
+}
+
+// applySchemaOverrides re-uses applyTagOverrides's resolution rules, just
+// operating on schema entries instead of bound SStructFieldValues.
+func applySchemaOverrides(sub []schemaField, overrides map[string]string) {
+	wrapped := make(SStructFieldValueSet, len(sub))
+	for i, s := range sub {
+		wrapped[i] = SStructFieldValue{Info: s.Info}
+	}
+	applyTagOverrides(wrapped, overrides)
+	for i := range sub {
+		sub[i].Info = wrapped[i].Info
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+type benchGuestIp struct {
+	GuestIpStart string `width:"16" charset:"ascii" nullable:"false" list:"user" update:"user" create:"required"`
+	GuestIpEnd   string `width:"16" charset:"ascii" nullable:"false" list:"user" update:"user" create:"required"`
+	GuestIpMask  int8   `nullable:"false" list:"user" update:"user" create:"required"`
+}
+
+type benchNetwork struct {
+	benchGuestIp
+	VlanId int    `nullable:"false" default:"1" list:"user" update:"user" create:"optional"`
+	WireId string `width:"36" charset:"ascii" nullable:"false" list:"user" create:"required"`
+}
+
+func TestTypeCacheInvalidate(t *testing.T) {
+	rt := reflect.TypeOf(benchNetwork{})
+
+	DefaultTypeCache.InvalidateAll()
+	first := DefaultTypeCache.schemaFor(rt)
+	second := DefaultTypeCache.schemaFor(rt)
+	if len(first) != len(second) {
+		t.Fatalf("schema length changed across cached calls: %d vs %d", len(first), len(second))
+	}
+
+	DefaultTypeCache.Invalidate(rt)
+	rebuilt := DefaultTypeCache.schemaFor(rt)
+	if len(rebuilt) != len(first) {
+		t.Fatalf("schema length changed after invalidation: %d vs %d", len(first), len(rebuilt))
+	}
+}
+
+func TestFetchStructFieldValueSetPointerEmbed(t *testing.T) {
+	type Base struct {
+		Name string `json:"name"`
+	}
+	type Top struct {
+		*Base
+		Age int `json:"age"`
+	}
+
+	set := FetchStructFieldValueSet(reflect.ValueOf(Top{Base: &Base{Name: "x"}, Age: 1}))
+	names := make(map[string]bool)
+	for _, sv := range set {
+		names[sv.Info.MarshalName()] = true
+	}
+	if !names["name"] || !names["age"] {
+		t.Fatalf("expected promoted name and age fields, got %v", names)
+	}
+
+	nilSet := FetchStructFieldValueSet(reflect.ValueOf(Top{Age: 1}))
+	for _, sv := range nilSet {
+		if sv.Info.MarshalName() == "name" && sv.Value.IsValid() {
+			t.Fatalf("expected zero Value for field promoted through a nil pointer embed")
+		}
+	}
+}
+
+func BenchmarkFetchStructFieldValueSetUncached(b *testing.B) {
+	j := benchNetwork{}
+	rt := reflect.TypeOf(j)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = buildSchema(rt, nil)
+	}
+}
+
+func BenchmarkFetchStructFieldValueSetCached(b *testing.B) {
+	j := benchNetwork{}
+	v := reflect.ValueOf(j)
+	DefaultTypeCache.schemaFor(reflect.TypeOf(j)) // warm the cache
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = FetchStructFieldValueSet(v)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValidatorFunc implements a single named rule of a `validate:"..."` tag,
+// e.g. the "min" in `validate:"min=1"`. value is the field being validated;
+// param is the text after "=" (empty for bare rules such as "required").
+type ValidatorFunc func(value reflect.Value, param string) error
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidatorFunc{
+		"required": validateRequired,
+		"min":      validateMin,
+		"max":      validateMax,
+		"regexp":   validateRegexp,
+		"oneof":    validateOneof,
+	}
+)
+
+// RegisterValidator registers a named validation rule usable in `validate`
+// tags, e.g. RegisterValidator("cidr", validateCIDR) enables `validate:"cidr"`.
+// Registering a name that already exists overwrites it.
+func RegisterValidator(name string, fn ValidatorFunc) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+}
+
+func lookupValidator(name string) (ValidatorFunc, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	fn, ok := validators[name]
+	return fn, ok
+}
+
+// FieldError is a single failed rule of a `validate` tag, addressed by the
+// JSON-pointer-style path built from the field's MarshalName().
+type FieldError struct {
+	Path string
+	Rule string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Path, e.Rule, e.Err)
+}
+
+// ValidationErrors aggregates every FieldError found by a single Validate
+// call. A nil *ValidationErrors (or one with no Errors) is not an error.
+type ValidationErrors struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate walks v (a struct or pointer to one) via FetchStructFieldValueSet
+// and runs every rule named in each field's `validate` tag, honoring
+// embedded-struct promotion and tag overrides. It returns a *ValidationErrors
+// aggregating every failure, or nil if every rule passed.
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("reflectutils: Validate requires a struct or struct pointer, got %s", rv.Kind())
+	}
+
+	set := FetchStructFieldValueSet(rv)
+	errs := &ValidationErrors{}
+	for _, sv := range set {
+		if sv.Info.Ignore {
+			continue
+		}
+		tag, ok := sv.Info.Tags["validate"]
+		if !ok || tag == "" {
+			continue
+		}
+		path := "/" + sv.Info.MarshalName()
+		for _, rule := range strings.Split(tag, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+			name, param := rule, ""
+			if idx := strings.IndexByte(rule, '='); idx >= 0 {
+				name, param = rule[:idx], rule[idx+1:]
+			}
+			fn, ok := lookupValidator(name)
+			if !ok {
+				errs.Errors = append(errs.Errors, &FieldError{
+					Path: path, Rule: name,
+					Err: fmt.Errorf("unknown validator %q", name),
+				})
+				continue
+			}
+			if err := fn(sv.Value, param); err != nil {
+				errs.Errors = append(errs.Errors, &FieldError{Path: path, Rule: name, Err: err})
+			}
+		}
+	}
+	if len(errs.Errors) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateRequired(value reflect.Value, _ string) error {
+	if isZero(value) {
+		return fmt.Errorf("is required")
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return true
+	}
+	return v.IsZero()
+}
+
+func validateMin(value reflect.Value, param string) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid min parameter %q", param)
+	}
+	n, err := numericValue(value)
+	if err != nil {
+		return err
+	}
+	if n < limit {
+		return fmt.Errorf("must be >= %v, got %v", limit, n)
+	}
+	return nil
+}
+
+func validateMax(value reflect.Value, param string) error {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid max parameter %q", param)
+	}
+	n, err := numericValue(value)
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("must be <= %v, got %v", limit, n)
+	}
+	return nil
+}
+
+func numericValue(value reflect.Value) (float64, error) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.String:
+		return float64(len(value.String())), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), nil
+	default:
+		return 0, fmt.Errorf("min/max not supported for kind %s", value.Kind())
+	}
+}
+
+func validateRegexp(value reflect.Value, param string) error {
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return fmt.Errorf("invalid regexp %q: %w", param, err)
+	}
+	if value.Kind() != reflect.String {
+		return fmt.Errorf("regexp not supported for kind %s", value.Kind())
+	}
+	if !re.MatchString(value.String()) {
+		return fmt.Errorf("must match %q, got %q", param, value.String())
+	}
+	return nil
+}
+
+func validateOneof(value reflect.Value, param string) error {
+	if value.Kind() != reflect.String {
+		return fmt.Errorf("oneof not supported for kind %s", value.Kind())
+	}
+	s := value.String()
+	for _, opt := range strings.Split(param, "|") {
+		if s == opt {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %q, got %q", param, s)
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Yunion
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectutils
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateBuiltinRules(t *testing.T) {
+	type sGuestIp struct {
+		GuestIpStart string `validate:"required"`
+		GuestIpMask  int8   `validate:"min=1,max=32"`
+		Status       string `validate:"oneof=init|running|error"`
+	}
+
+	cases := []struct {
+		Name    string
+		Object  sGuestIp
+		WantErr bool
+	}{
+		{
+			Name:    "valid",
+			Object:  sGuestIp{GuestIpStart: "10.168.10.1", GuestIpMask: 24, Status: "running"},
+			WantErr: false,
+		},
+		{
+			Name:    "missing required",
+			Object:  sGuestIp{GuestIpMask: 24, Status: "running"},
+			WantErr: true,
+		},
+		{
+			Name:    "mask out of range",
+			Object:  sGuestIp{GuestIpStart: "10.168.10.1", GuestIpMask: 64, Status: "running"},
+			WantErr: true,
+		},
+		{
+			Name:    "bad status",
+			Object:  sGuestIp{GuestIpStart: "10.168.10.1", GuestIpMask: 24, Status: "bogus"},
+			WantErr: true,
+		},
+	}
+	for _, c := range cases {
+		err := Validate(&c.Object)
+		if (err != nil) != c.WantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", c.Name, err, c.WantErr)
+		}
+	}
+}
+
+func TestValidateEmbeddedAndOverride(t *testing.T) {
+	type Base struct {
+		Name string `validate:"required"`
+	}
+	type Top struct {
+		Base `"name->validate":"min=3"`
+	}
+
+	if err := Validate(&Top{Base: Base{Name: "ab"}}); err == nil {
+		t.Errorf("expected min=3 override to reject a 2-char name")
+	}
+	if err := Validate(&Top{Base: Base{Name: "abc"}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRegisterValidatorCustomRule(t *testing.T) {
+	RegisterValidator("cidr", func(value reflect.Value, _ string) error {
+		if value.Kind() != reflect.String {
+			return nil
+		}
+		if _, _, err := net.ParseCIDR(value.String()); err != nil {
+			return err
+		}
+		return nil
+	})
+
+	type sNetwork struct {
+		Cidr string `validate:"cidr"`
+	}
+
+	if err := Validate(&sNetwork{Cidr: "10.168.10.0/24"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := Validate(&sNetwork{Cidr: "not-a-cidr"})
+	if err == nil || !strings.Contains(err.Error(), "/cidr") {
+		t.Errorf("expected a /cidr path error, got %v", err)
+	}
+}